@@ -0,0 +1,191 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/xds/internal/xdsclient/bootstrap"
+)
+
+// fakeConnTransport is a StreamTransport, also implementing
+// connectivityStateWatcher, whose connectivity state is driven explicitly by
+// a test and whose NewStream always fails, to exercise the gRFC A57
+// failure-mode behavior without a real gRPC channel.
+type fakeConnTransport struct {
+	newStreamErr error
+
+	mu       sync.Mutex
+	state    connectivity.State
+	notifyCh chan struct{}
+}
+
+func newFakeConnTransport(initial connectivity.State, newStreamErr error) *fakeConnTransport {
+	return &fakeConnTransport{newStreamErr: newStreamErr, state: initial, notifyCh: make(chan struct{})}
+}
+
+func (f *fakeConnTransport) NewStream(context.Context) (Stream, error) {
+	return nil, f.newStreamErr
+}
+
+func (f *fakeConnTransport) Close() {}
+
+func (f *fakeConnTransport) GetState() connectivity.State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *fakeConnTransport) WaitForStateChange(ctx context.Context, _ connectivity.State) bool {
+	f.mu.Lock()
+	ch := f.notifyCh
+	f.mu.Unlock()
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// setState updates the reported connectivity state and wakes up any
+// in-flight WaitForStateChange call.
+func (f *fakeConnTransport) setState(s connectivity.State) {
+	f.mu.Lock()
+	f.state = s
+	old := f.notifyCh
+	f.notifyCh = make(chan struct{})
+	f.mu.Unlock()
+	close(old)
+}
+
+type fakeTransportBuilder struct {
+	st StreamTransport
+}
+
+func (f fakeTransportBuilder) Build(Options) (StreamTransport, error) {
+	return f.st, nil
+}
+
+// TestA57ConnectivityFailureModeBehavior drives connectivityStateMonitor and
+// adsRunner through the gRFC A57 sequence: channel goes to TRANSIENT_FAILURE,
+// the management server is reported unreachable once a stream attempt fails
+// without a single response, and the failure is cleared once the channel
+// becomes READY again.
+func TestA57ConnectivityFailureModeBehavior(t *testing.T) {
+	fc := newFakeConnTransport(connectivity.Ready, errors.New("stream creation fails for this test"))
+
+	var (
+		channelFailureCh      = make(chan struct{}, 10)
+		channelReadyCh        = make(chan struct{}, 10)
+		connectivityFailureCh = make(chan error, 10)
+	)
+
+	tr, err := New(Options{
+		ServerCfg:             bootstrap.ServerConfig{ServerURI: "fake-server"},
+		UpdateHandler:         func(ResourceUpdate) error { return nil },
+		TransportBuilder:      fakeTransportBuilder{st: fc},
+		StreamErrorHandler:    func(error) {},
+		Backoff:               func(int) time.Duration { return time.Millisecond },
+		Logger:                grpclog.NewPrefixLogger(nil, "transport-test"),
+		OnChannelFailure:      func() { channelFailureCh <- struct{}{} },
+		OnChannelReady:        func() { channelReadyCh <- struct{}{} },
+		OnConnectivityFailure: func(err error) { connectivityFailureCh <- err },
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer tr.Close()
+
+	// The channel starts READY. Stream creation keeps failing, but since the
+	// channel isn't in TRANSIENT_FAILURE, the server must not be reported as
+	// unreachable yet.
+	select {
+	case <-connectivityFailureCh:
+		t.Fatalf("OnConnectivityFailure called while the channel was READY")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fc.setState(connectivity.TransientFailure)
+	select {
+	case <-channelFailureCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for OnChannelFailure")
+	}
+
+	select {
+	case <-connectivityFailureCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for OnConnectivityFailure")
+	}
+	if got := tr.ChannelConnectivityStateForTesting(); got != connectivity.TransientFailure {
+		t.Errorf("ChannelConnectivityStateForTesting() = %v, want %v", got, connectivity.TransientFailure)
+	}
+
+	fc.setState(connectivity.Ready)
+	select {
+	case <-channelReadyCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for OnChannelReady")
+	}
+}
+
+// TestA57ConnectivityFailureModeBehaviorAlreadyInTransientFailure verifies
+// that, when the channel is already in TRANSIENT_FAILURE by the time
+// connectivityStateMonitor starts watching it (e.g. an unreachable
+// ServerURI), the A57 failure signal fires from that initial state rather
+// than waiting for a subsequent state transition that may never come.
+func TestA57ConnectivityFailureModeBehaviorAlreadyInTransientFailure(t *testing.T) {
+	fc := newFakeConnTransport(connectivity.TransientFailure, errors.New("stream creation fails for this test"))
+
+	channelFailureCh := make(chan struct{}, 10)
+	connectivityFailureCh := make(chan error, 10)
+
+	tr, err := New(Options{
+		ServerCfg:             bootstrap.ServerConfig{ServerURI: "fake-server"},
+		UpdateHandler:         func(ResourceUpdate) error { return nil },
+		TransportBuilder:      fakeTransportBuilder{st: fc},
+		StreamErrorHandler:    func(error) {},
+		Backoff:               func(int) time.Duration { return time.Millisecond },
+		Logger:                grpclog.NewPrefixLogger(nil, "transport-test"),
+		OnChannelFailure:      func() { channelFailureCh <- struct{}{} },
+		OnConnectivityFailure: func(err error) { connectivityFailureCh <- err },
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer tr.Close()
+
+	select {
+	case <-channelFailureCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for OnChannelFailure seeded from the initial TRANSIENT_FAILURE state")
+	}
+
+	select {
+	case <-connectivityFailureCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for OnConnectivityFailure seeded from the initial TRANSIENT_FAILURE state")
+	}
+}
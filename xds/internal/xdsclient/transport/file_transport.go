@@ -0,0 +1,224 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+)
+
+// fileStreamPollInterval is how often a fileStream re-scans its directory
+// for newly dropped response files once it has exhausted the files it
+// already knows about. Overridable in tests.
+var fileStreamPollInterval = time.Second
+
+// FileTransportBuilder builds a StreamTransport which, instead of talking to
+// a gRPC management server, replays DiscoveryResponse messages read from a
+// directory of protojson files. The directory is polled, so files dropped in
+// after a stream has already been created are picked up and served too. This
+// is useful for bootstrap testing and for reproducing ADS failures offline,
+// without needing a live management server.
+//
+// The directory to watch is taken from Options.ServerCfg.ServerURI.
+type FileTransportBuilder struct{}
+
+// Build creates a new FileTransport reading responses from the directory
+// named in opts.ServerCfg.ServerURI.
+func (FileTransportBuilder) Build(opts Options) (StreamTransport, error) {
+	if opts.ServerCfg.ServerURI == "" {
+		return nil, errors.New("missing response directory (ServerCfg.ServerURI) when creating a new FileTransport")
+	}
+	return &FileTransport{dir: opts.ServerCfg.ServerURI}, nil
+}
+
+// FileTransport is a StreamTransport implementation which reads
+// DiscoveryResponse (or DeltaDiscoveryResponse) protojson files from a
+// directory, in lexical filename order, and feeds them through the same
+// UpdateHandlerFunc/DeltaUpdateHandlerFunc pipeline a real ADS stream would.
+// It never sends anything back to a management server; requests (including
+// ACKs/NACKs) are simply dropped.
+type FileTransport struct {
+	dir string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewStream returns a Stream which replays the contents of dir, polling for
+// new files once the ones already present have been served. A new Stream may
+// be created once per ADS stream (re)connection attempt, mirroring how
+// adsRunner treats a real gRPC stream.
+func (f *FileTransport) NewStream(ctx context.Context) (Stream, error) {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	if closed {
+		return nil, errors.New("FileTransport is closed")
+	}
+
+	names, err := responseFileNames(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list response files in %q: %v", f.dir, err)
+	}
+	return &fileStream{ctx: ctx, dir: f.dir, names: names}, nil
+}
+
+// Close marks the FileTransport as closed, so that NewStream fails from then
+// on. Streams already handed out are not affected: they keep reading and
+// polling the directory until their context is done.
+func (f *FileTransport) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// responseFileNames returns the names of the response files in dir, sorted
+// lexically so that replay order is deterministic and reproducible.
+func responseFileNames(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		names = append(names, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// fileStream is a Stream backed by an ordered list of response files in dir.
+// Send is a no-op: there's no management server on the other end to ACK/NACK
+// to. Recv serves one file per call, in order; once the files known so far
+// are exhausted, it polls dir for newly dropped files instead of returning,
+// since a real ADS stream would simply stay open awaiting the next push.
+type fileStream struct {
+	ctx context.Context
+	dir string
+
+	mu    sync.Mutex
+	names []string
+	next  int
+}
+
+func (f *fileStream) Send(*DiscoveryRequest) error {
+	// Requests, including ACKs/NACKs, have nowhere to go: there is no
+	// management server on the other end of a FileTransport.
+	return nil
+}
+
+func (f *fileStream) Recv() (*DiscoveryResponse, error) {
+	name, err := f.nextFile()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response file %q: %v", name, err)
+	}
+
+	// Try the delta response shape first, falling back to SotW; a directory
+	// of fakes is expected to consistently contain one or the other.
+	delta := &v3discoverypb.DeltaDiscoveryResponse{}
+	if err := protojson.Unmarshal(raw, delta); err == nil && delta.GetTypeUrl() != "" {
+		added := make([]*Resource, len(delta.GetResources()))
+		for i, r := range delta.GetResources() {
+			added[i] = &Resource{Name: r.GetName(), Version: r.GetVersion(), Proto: r.GetResource()}
+		}
+		return &DiscoveryResponse{
+			TypeURL:           delta.GetTypeUrl(),
+			Added:             added,
+			RemovedResources:  delta.GetRemovedResources(),
+			SystemVersionInfo: delta.GetSystemVersionInfo(),
+			Nonce:             delta.GetNonce(),
+		}, nil
+	}
+
+	resp := &v3discoverypb.DiscoveryResponse{}
+	if err := protojson.Unmarshal(raw, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response file %q as a DiscoveryResponse or DeltaDiscoveryResponse: %v", name, err)
+	}
+	return &DiscoveryResponse{
+		TypeURL:     resp.GetTypeUrl(),
+		Resources:   resp.GetResources(),
+		VersionInfo: resp.GetVersionInfo(),
+		Nonce:       resp.GetNonce(),
+	}, nil
+}
+
+// nextFile returns the path of the next response file to serve, blocking and
+// periodically re-scanning f.dir if the files already known about have all
+// been served, until a new one appears or ctx is done.
+func (f *fileStream) nextFile() (string, error) {
+	f.mu.Lock()
+	idx := f.next
+	names := f.names
+	f.mu.Unlock()
+
+	if idx < len(names) {
+		f.mu.Lock()
+		f.next++
+		f.mu.Unlock()
+		return names[idx], nil
+	}
+
+	ticker := time.NewTicker(fileStreamPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return "", f.ctx.Err()
+		case <-ticker.C:
+			names, err := responseFileNames(f.dir)
+			if err != nil {
+				return "", fmt.Errorf("failed to list response files in %q: %v", f.dir, err)
+			}
+			f.mu.Lock()
+			f.names = names
+			idx := f.next
+			if idx < len(names) {
+				f.next++
+				f.mu.Unlock()
+				return names[idx], nil
+			}
+			f.mu.Unlock()
+		}
+	}
+}
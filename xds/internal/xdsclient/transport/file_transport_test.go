@@ -0,0 +1,111 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/xds/internal/xdsclient/bootstrap"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+)
+
+func writeResponseFile(t *testing.T, dir, name string, resp *v3discoverypb.DiscoveryResponse) {
+	t.Helper()
+	raw, err := protojson.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to marshal response: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), raw, 0644); err != nil {
+		t.Fatalf("failed to write response file %q: %v", name, err)
+	}
+}
+
+// TestFileTransportReplaysAndPollsForNewFiles verifies that a fileStream
+// replays the response files present in its directory in lexical order, and
+// that it keeps polling the directory for new files once it has caught up,
+// instead of returning an error or blocking forever.
+func TestFileTransportReplaysAndPollsForNewFiles(t *testing.T) {
+	orig := fileStreamPollInterval
+	fileStreamPollInterval = 10 * time.Millisecond
+	defer func() { fileStreamPollInterval = orig }()
+
+	dir := t.TempDir()
+	writeResponseFile(t, dir, "0001.json", &v3discoverypb.DiscoveryResponse{TypeUrl: "type.A", VersionInfo: "1", Nonce: "n1"})
+	writeResponseFile(t, dir, "0002.json", &v3discoverypb.DiscoveryResponse{TypeUrl: "type.A", VersionInfo: "2", Nonce: "n2"})
+
+	ft, err := (FileTransportBuilder{}).Build(Options{ServerCfg: bootstrap.ServerConfig{ServerURI: dir}})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	defer ft.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := ft.NewStream(ctx)
+	if err != nil {
+		t.Fatalf("NewStream() failed: %v", err)
+	}
+
+	for i, want := range []string{"1", "2"} {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() #%d failed: %v", i, err)
+		}
+		if resp.VersionInfo != want {
+			t.Errorf("Recv() #%d VersionInfo = %q, want %q", i, resp.VersionInfo, want)
+		}
+	}
+
+	// The known files are exhausted; Recv() should now be polling dir rather
+	// than returning.
+	type recvResult struct {
+		resp *DiscoveryResponse
+		err  error
+	}
+	recvCh := make(chan recvResult, 1)
+	go func() {
+		resp, err := stream.Recv()
+		recvCh <- recvResult{resp, err}
+	}()
+
+	select {
+	case <-recvCh:
+		t.Fatalf("Recv() returned before a new file was dropped into the directory")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writeResponseFile(t, dir, "0003.json", &v3discoverypb.DiscoveryResponse{TypeUrl: "type.A", VersionInfo: "3", Nonce: "n3"})
+
+	select {
+	case r := <-recvCh:
+		if r.err != nil {
+			t.Fatalf("Recv() #3 failed: %v", r.err)
+		}
+		if r.resp.VersionInfo != "3" {
+			t.Errorf("Recv() #3 VersionInfo = %q, want %q", r.resp.VersionInfo, "3")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for Recv() to pick up the newly dropped file")
+	}
+}
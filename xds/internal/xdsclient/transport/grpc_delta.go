@@ -0,0 +1,133 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/internal/pretty"
+
+	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	v3adsgrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+type deltaStream = v3adsgrpc.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
+
+// grpcDeltaTransportBuilder is the default TransportBuilder used whenever
+// Options.TransportBuilder is unset and the management server is configured
+// to speak the delta (incremental) variant of the xDS transport protocol.
+type grpcDeltaTransportBuilder struct{}
+
+func (grpcDeltaTransportBuilder) Build(opts Options) (StreamTransport, error) {
+	// Delegate dialing to the SotW builder; the two variants share the same
+	// gRPC ClientConn setup and only differ in which RPC they invoke.
+	st, err := (grpcSotWTransportBuilder{}).Build(opts)
+	if err != nil {
+		return nil, err
+	}
+	sotw := st.(*grpcSotWStreamTransport)
+	return &grpcDeltaStreamTransport{cc: sotw.cc, nodeProto: sotw.nodeProto, logger: sotw.logger}, nil
+}
+
+// grpcDeltaStreamTransport is the default, gRPC based, implementation of
+// StreamTransport, speaking the delta variant of the ADS protocol
+// (DeltaAggregatedResources).
+type grpcDeltaStreamTransport struct {
+	cc        *grpc.ClientConn
+	nodeProto *v3corepb.Node
+	logger    *grpclog.PrefixLogger
+}
+
+func (g *grpcDeltaStreamTransport) NewStream(ctx context.Context) (Stream, error) {
+	// See the comment in grpcSotWStreamTransport.NewStream for why
+	// WaitForReady is intentionally not set here.
+	s, err := v3adsgrpc.NewAggregatedDiscoveryServiceClient(g.cc).DeltaAggregatedResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcDeltaStream{stream: s, nodeProto: g.nodeProto, logger: g.logger}, nil
+}
+
+func (g *grpcDeltaStreamTransport) Close() {
+	g.cc.Close()
+}
+
+func (g *grpcDeltaStreamTransport) GetState() connectivity.State {
+	return g.cc.GetState()
+}
+
+func (g *grpcDeltaStreamTransport) WaitForStateChange(ctx context.Context, source connectivity.State) bool {
+	return g.cc.WaitForStateChange(ctx, source)
+}
+
+// grpcDeltaStream adapts a delta ADS gRPC stream to the protocol-agnostic
+// Stream interface.
+type grpcDeltaStream struct {
+	stream    deltaStream
+	nodeProto *v3corepb.Node
+	logger    *grpclog.PrefixLogger
+}
+
+func (g *grpcDeltaStream) Send(req *DiscoveryRequest) error {
+	r := &v3discoverypb.DeltaDiscoveryRequest{
+		Node:                     g.nodeProto,
+		TypeUrl:                  req.TypeURL,
+		ResourceNamesSubscribe:   req.ResourceNames,
+		ResourceNamesUnsubscribe: req.ResourceNamesUnsubscribe,
+		InitialResourceVersions:  req.InitialResourceVersions,
+		ResponseNonce:            req.ResponseNonce,
+	}
+	if req.ErrorDetail != nil {
+		r.ErrorDetail = &statuspb.Status{
+			Code: int32(codes.InvalidArgument), Message: req.ErrorDetail.Error(),
+		}
+	}
+	if err := g.stream.Send(r); err != nil {
+		return fmt.Errorf("sending Delta ADS request %s failed: %v", pretty.ToJSON(r), err)
+	}
+	g.logger.Debugf("Delta ADS request sent: %v", pretty.ToJSON(r))
+	return nil
+}
+
+func (g *grpcDeltaStream) Recv() (*DiscoveryResponse, error) {
+	resp, err := g.stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Delta ADS response: %v", err)
+	}
+	g.logger.Infof("Delta ADS response received, type: %v", resp.GetTypeUrl())
+	g.logger.Debugf("Delta ADS response received: %v", pretty.ToJSON(resp))
+
+	added := make([]*Resource, len(resp.GetResources()))
+	for i, r := range resp.GetResources() {
+		added[i] = &Resource{Name: r.GetName(), Version: r.GetVersion(), Proto: r.GetResource()}
+	}
+	return &DiscoveryResponse{
+		TypeURL:           resp.GetTypeUrl(),
+		Added:             added,
+		RemovedResources:  resp.GetRemovedResources(),
+		SystemVersionInfo: resp.GetSystemVersionInfo(),
+		Nonce:             resp.GetNonce(),
+	}, nil
+}
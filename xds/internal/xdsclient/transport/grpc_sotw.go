@@ -0,0 +1,155 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/internal/pretty"
+	"google.golang.org/grpc/keepalive"
+
+	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	v3adsgrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+type adsStream = v3adsgrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+
+// For overriding in unit tests.
+var grpcDial = grpc.Dial
+
+// grpcSotWTransportBuilder is the default TransportBuilder, used whenever
+// Options.TransportBuilder is unset and the management server is configured
+// to speak the state-of-the-world (SotW) variant of the xDS transport
+// protocol.
+type grpcSotWTransportBuilder struct{}
+
+func (grpcSotWTransportBuilder) Build(opts Options) (StreamTransport, error) {
+	switch {
+	case opts.ServerCfg.ServerURI == "":
+		return nil, errors.New("missing server URI when creating a new transport")
+	case opts.ServerCfg.Creds == nil:
+		return nil, errors.New("missing credentials when creating a new transport")
+	}
+
+	// Dial the xDS management with the passed in credentials.
+	dopts := []grpc.DialOption{
+		opts.ServerCfg.Creds,
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			// We decided to use these sane defaults in all languages, and
+			// kicked the can down the road as far making these configurable.
+			Time:    5 * time.Minute,
+			Timeout: 20 * time.Second,
+		}),
+	}
+	cc, err := grpcDial(opts.ServerCfg.ServerURI, dopts...)
+	if err != nil {
+		// An error from a non-blocking dial indicates something serious.
+		return nil, fmt.Errorf("failed to create a transport to the management server %q: %v", opts.ServerCfg.ServerURI, err)
+	}
+	return &grpcSotWStreamTransport{
+		cc:        cc,
+		nodeProto: opts.NodeProto,
+		logger:    opts.Logger,
+	}, nil
+}
+
+// grpcSotWStreamTransport is the default, gRPC based, implementation of
+// StreamTransport, speaking the state-of-the-world variant of the ADS
+// protocol (StreamAggregatedResources).
+type grpcSotWStreamTransport struct {
+	cc        *grpc.ClientConn
+	nodeProto *v3corepb.Node
+	logger    *grpclog.PrefixLogger
+}
+
+func (g *grpcSotWStreamTransport) NewStream(ctx context.Context) (Stream, error) {
+	// WaitForReady is intentionally not set here. Per gRFC A57, stream
+	// creation must fail fast when the channel is in TRANSIENT_FAILURE
+	// (rather than waiting for the channel to become READY) so that Transport
+	// can drive its own backoff and connectivity-state-based failure-mode
+	// behavior instead of blocking indefinitely inside the RPC layer.
+	s, err := v3adsgrpc.NewAggregatedDiscoveryServiceClient(g.cc).StreamAggregatedResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcSotWStream{stream: s, nodeProto: g.nodeProto, logger: g.logger}, nil
+}
+
+func (g *grpcSotWStreamTransport) Close() {
+	g.cc.Close()
+}
+
+func (g *grpcSotWStreamTransport) GetState() connectivity.State {
+	return g.cc.GetState()
+}
+
+func (g *grpcSotWStreamTransport) WaitForStateChange(ctx context.Context, source connectivity.State) bool {
+	return g.cc.WaitForStateChange(ctx, source)
+}
+
+// grpcSotWStream adapts a SotW ADS gRPC stream to the protocol-agnostic
+// Stream interface.
+type grpcSotWStream struct {
+	stream    adsStream
+	nodeProto *v3corepb.Node
+	logger    *grpclog.PrefixLogger
+}
+
+func (g *grpcSotWStream) Send(req *DiscoveryRequest) error {
+	r := &v3discoverypb.DiscoveryRequest{
+		Node:          g.nodeProto,
+		TypeUrl:       req.TypeURL,
+		ResourceNames: req.ResourceNames,
+		VersionInfo:   req.VersionInfo,
+		ResponseNonce: req.ResponseNonce,
+	}
+	if req.ErrorDetail != nil {
+		r.ErrorDetail = &statuspb.Status{
+			Code: int32(codes.InvalidArgument), Message: req.ErrorDetail.Error(),
+		}
+	}
+	if err := g.stream.Send(r); err != nil {
+		return fmt.Errorf("sending ADS request %s failed: %v", pretty.ToJSON(r), err)
+	}
+	g.logger.Debugf("ADS request sent: %v", pretty.ToJSON(r))
+	return nil
+}
+
+func (g *grpcSotWStream) Recv() (*DiscoveryResponse, error) {
+	resp, err := g.stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ADS response: %v", err)
+	}
+	g.logger.Infof("ADS response received, type: %v", resp.GetTypeUrl())
+	g.logger.Debugf("ADS response received: %v", pretty.ToJSON(resp))
+	return &DiscoveryResponse{
+		TypeURL:     resp.GetTypeUrl(),
+		Resources:   resp.GetResources(),
+		VersionInfo: resp.GetVersionInfo(),
+		Nonce:       resp.GetNonce(),
+	}, nil
+}
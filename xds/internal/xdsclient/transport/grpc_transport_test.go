@@ -0,0 +1,188 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	v3adsgrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+)
+
+// fakeADSClientStream is a v3adsgrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+// which only implements Send/Recv; the embedded nil interface satisfies the
+// remaining grpc.ClientStream methods, which grpcSotWStream never calls.
+type fakeADSClientStream struct {
+	v3adsgrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+
+	sentReqs []*v3discoverypb.DiscoveryRequest
+	recvResp *v3discoverypb.DiscoveryResponse
+	recvErr  error
+}
+
+func (f *fakeADSClientStream) Send(r *v3discoverypb.DiscoveryRequest) error {
+	f.sentReqs = append(f.sentReqs, r)
+	return nil
+}
+
+func (f *fakeADSClientStream) Recv() (*v3discoverypb.DiscoveryResponse, error) {
+	return f.recvResp, f.recvErr
+}
+
+func TestGRPCSotWStreamSendTranslatesFields(t *testing.T) {
+	fake := &fakeADSClientStream{}
+	node := &v3corepb.Node{Id: "node-1"}
+	s := &grpcSotWStream{stream: fake, nodeProto: node, logger: grpclog.NewPrefixLogger(nil, "test")}
+
+	if err := s.Send(&DiscoveryRequest{
+		TypeURL:       "type.A",
+		ResourceNames: []string{"r1", "r2"},
+		VersionInfo:   "v1",
+		ResponseNonce: "n1",
+		ErrorDetail:   errors.New("bad resource"),
+	}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	if len(fake.sentReqs) != 1 {
+		t.Fatalf("got %d requests sent on the wire, want 1", len(fake.sentReqs))
+	}
+	got := fake.sentReqs[0]
+	if got.Node != node || got.TypeUrl != "type.A" || got.VersionInfo != "v1" || got.ResponseNonce != "n1" {
+		t.Errorf("Send() produced %+v, want Node=%v TypeUrl=type.A VersionInfo=v1 ResponseNonce=n1", got, node)
+	}
+	if want := []string{"r1", "r2"}; !reflect.DeepEqual(got.ResourceNames, want) {
+		t.Errorf("ResourceNames = %v, want %v", got.ResourceNames, want)
+	}
+	if got.ErrorDetail == nil || got.ErrorDetail.Code != int32(codes.InvalidArgument) || got.ErrorDetail.Message != "bad resource" {
+		t.Errorf("ErrorDetail = %+v, want Code=%v Message=bad resource", got.ErrorDetail, codes.InvalidArgument)
+	}
+}
+
+func TestGRPCSotWStreamRecvTranslatesFields(t *testing.T) {
+	resource := &anypb.Any{TypeUrl: "type.A", Value: []byte("payload")}
+	fake := &fakeADSClientStream{recvResp: &v3discoverypb.DiscoveryResponse{
+		TypeUrl:     "type.A",
+		Resources:   []*anypb.Any{resource},
+		VersionInfo: "v2",
+		Nonce:       "n2",
+	}}
+	s := &grpcSotWStream{stream: fake, logger: grpclog.NewPrefixLogger(nil, "test")}
+
+	got, err := s.Recv()
+	if err != nil {
+		t.Fatalf("Recv() failed: %v", err)
+	}
+	want := &DiscoveryResponse{
+		TypeURL:     "type.A",
+		Resources:   []*anypb.Any{resource},
+		VersionInfo: "v2",
+		Nonce:       "n2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Recv() = %+v, want %+v", got, want)
+	}
+}
+
+// fakeDeltaClientStream is a v3adsgrpc.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
+// which only implements Send/Recv.
+type fakeDeltaClientStream struct {
+	v3adsgrpc.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
+
+	sentReqs []*v3discoverypb.DeltaDiscoveryRequest
+	recvResp *v3discoverypb.DeltaDiscoveryResponse
+	recvErr  error
+}
+
+func (f *fakeDeltaClientStream) Send(r *v3discoverypb.DeltaDiscoveryRequest) error {
+	f.sentReqs = append(f.sentReqs, r)
+	return nil
+}
+
+func (f *fakeDeltaClientStream) Recv() (*v3discoverypb.DeltaDiscoveryResponse, error) {
+	return f.recvResp, f.recvErr
+}
+
+func TestGRPCDeltaStreamSendTranslatesFields(t *testing.T) {
+	fake := &fakeDeltaClientStream{}
+	node := &v3corepb.Node{Id: "node-1"}
+	s := &grpcDeltaStream{stream: fake, nodeProto: node, logger: grpclog.NewPrefixLogger(nil, "test")}
+
+	if err := s.Send(&DiscoveryRequest{
+		TypeURL:                  "type.A",
+		ResourceNames:            []string{"r1"},
+		ResourceNamesUnsubscribe: []string{"r2"},
+		InitialResourceVersions:  map[string]string{"r1": "v1"},
+		ResponseNonce:            "n1",
+	}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	if len(fake.sentReqs) != 1 {
+		t.Fatalf("got %d requests sent on the wire, want 1", len(fake.sentReqs))
+	}
+	got := fake.sentReqs[0]
+	if got.Node != node || got.TypeUrl != "type.A" || got.ResponseNonce != "n1" {
+		t.Errorf("Send() produced %+v, want Node=%v TypeUrl=type.A ResponseNonce=n1", got, node)
+	}
+	if want := []string{"r1"}; !reflect.DeepEqual(got.ResourceNamesSubscribe, want) {
+		t.Errorf("ResourceNamesSubscribe = %v, want %v", got.ResourceNamesSubscribe, want)
+	}
+	if want := []string{"r2"}; !reflect.DeepEqual(got.ResourceNamesUnsubscribe, want) {
+		t.Errorf("ResourceNamesUnsubscribe = %v, want %v", got.ResourceNamesUnsubscribe, want)
+	}
+	if want := map[string]string{"r1": "v1"}; !reflect.DeepEqual(got.InitialResourceVersions, want) {
+		t.Errorf("InitialResourceVersions = %v, want %v", got.InitialResourceVersions, want)
+	}
+}
+
+func TestGRPCDeltaStreamRecvTranslatesFields(t *testing.T) {
+	resource := &anypb.Any{TypeUrl: "type.A", Value: []byte("payload")}
+	fake := &fakeDeltaClientStream{recvResp: &v3discoverypb.DeltaDiscoveryResponse{
+		TypeUrl: "type.A",
+		Resources: []*v3discoverypb.Resource{
+			{Name: "r1", Version: "v1", Resource: resource},
+		},
+		RemovedResources:  []string{"r2"},
+		SystemVersionInfo: "s1",
+		Nonce:             "n1",
+	}}
+	s := &grpcDeltaStream{stream: fake, logger: grpclog.NewPrefixLogger(nil, "test")}
+
+	got, err := s.Recv()
+	if err != nil {
+		t.Fatalf("Recv() failed: %v", err)
+	}
+	want := &DiscoveryResponse{
+		TypeURL:           "type.A",
+		Added:             []*Resource{{Name: "r1", Version: "v1", Proto: resource}},
+		RemovedResources:  []string{"r2"},
+		SystemVersionInfo: "s1",
+		Nonce:             "n1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Recv() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// DiscoveryRequest is a protocol-agnostic representation of an xDS request.
+// StreamTransport implementations translate it to whatever wire format they
+// actually speak (e.g. DiscoveryRequest or DeltaDiscoveryRequest).
+type DiscoveryRequest struct {
+	// TypeURL is the resource type being requested.
+	TypeURL string
+	// ResourceNames is, for the SotW protocol variant, the complete set of
+	// resource names of interest. For the delta variant, it is instead the
+	// set of resource names newly subscribed to since the previous request.
+	ResourceNames []string
+	// ResourceNamesUnsubscribe is the set of resource names newly
+	// unsubscribed from since the previous request. Only meaningful to
+	// StreamTransport implementations speaking the delta protocol variant;
+	// SotW implementations ignore it.
+	ResourceNamesUnsubscribe []string
+	// InitialResourceVersions is, for the delta protocol variant, the most
+	// recently accepted version of every currently subscribed resource,
+	// populated on the first request of a (re)started stream so that the
+	// management server can compute a diff against what the client already
+	// has. Ignored by SotW implementations.
+	InitialResourceVersions map[string]string
+	// VersionInfo is the most recently accepted version for TypeURL. Only
+	// meaningful to the SotW protocol variant.
+	VersionInfo string
+	// ResponseNonce is the nonce from the response being ACKed or NACKed, and
+	// is empty for the first request on a (re)started stream.
+	ResponseNonce string
+	// ErrorDetail is non-nil when this request is a NACK of the response
+	// identified by ResponseNonce.
+	ErrorDetail error
+}
+
+// DiscoveryResponse is a protocol-agnostic representation of an xDS
+// response, as translated from the wire format by a StreamTransport
+// implementation.
+type DiscoveryResponse struct {
+	// TypeURL is the resource type URL for the resources below.
+	TypeURL string
+	// Resources is, for the SotW protocol variant, the complete set of
+	// resources of the given type. Unused by the delta variant, which
+	// populates Added and RemovedResources instead.
+	Resources []*anypb.Any
+	// VersionInfo is, for the SotW protocol variant, the version of the
+	// overall response.
+	VersionInfo string
+	// Added is, for the delta protocol variant, the list of resources which
+	// were added or updated since the previous response.
+	Added []*Resource
+	// RemovedResources is, for the delta protocol variant, the list of
+	// resource names no longer present on the management server.
+	RemovedResources []string
+	// SystemVersionInfo is, for the delta protocol variant, the version of
+	// the overall response. Informational only.
+	SystemVersionInfo string
+	// Nonce is the value to be echoed back to the management server, as an
+	// acknowledgement of this response, in the next request on this stream.
+	Nonce string
+}
+
+// Stream represents a single, long-lived, bidirectional xDS RPC stream to a
+// management server.
+type Stream interface {
+	// Send sends req on the stream. It is called from a single goroutine at
+	// a time, and must not be called concurrently with another Send.
+	Send(req *DiscoveryRequest) error
+	// Recv blocks until a response is received from the management server,
+	// or the stream breaks, in which case a non-nil error is returned.
+	Recv() (*DiscoveryResponse, error)
+}
+
+// StreamTransport creates Streams to a single management server, using
+// whatever wire protocol the implementation chooses (SotW gRPC ADS, delta
+// gRPC ADS, a local filesystem, an xds-relay-style aggregating proxy, etc).
+// This is the extension point that allows alternative xDS control-plane
+// protocols to be plugged into the xdsclient without forking it.
+type StreamTransport interface {
+	// NewStream creates a new Stream to the management server. The returned
+	// Stream is only valid for the lifetime of ctx.
+	NewStream(ctx context.Context) (Stream, error)
+	// Close releases any resources (e.g. a gRPC ClientConn) held by the
+	// StreamTransport.
+	Close()
+}
+
+// TransportBuilder creates a StreamTransport for a given set of Options.
+type TransportBuilder interface {
+	// Build creates a new StreamTransport for exclusive use by a single
+	// Transport.
+	Build(opts Options) (StreamTransport, error)
+}
+
+// connectivityStateWatcher is optionally implemented by a StreamTransport
+// that is backed by a gRPC ClientConn, allowing Transport to observe the
+// connectivity state of the underlying channel (used to implement the gRFC
+// A57 failure-mode behavior). StreamTransport implementations which have no
+// notion of a gRPC channel (e.g. FileTransport) do not implement this.
+type connectivityStateWatcher interface {
+	GetState() connectivity.State
+	WaitForStateChange(ctx context.Context, source connectivity.State) bool
+}
+
+// defaultTransportBuilder returns the TransportBuilder to use when the user
+// has not configured one explicitly: the gRPC based SotW or delta ADS
+// implementation, depending on useDelta.
+func defaultTransportBuilder(useDelta bool) TransportBuilder {
+	if useDelta {
+		return grpcDeltaTransportBuilder{}
+	}
+	return grpcSotWTransportBuilder{}
+}
@@ -26,54 +26,55 @@ import (
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/internal/backoff"
 	"google.golang.org/grpc/internal/buffer"
 	"google.golang.org/grpc/internal/grpclog"
-	"google.golang.org/grpc/internal/pretty"
-	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/xds/internal/xdsclient/bootstrap"
 	"google.golang.org/grpc/xds/internal/xdsclient/load"
 	"google.golang.org/grpc/xds/internal/xdsclient/xdsresource"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
-	v3adsgrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
-	v3discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
-	statuspb "google.golang.org/genproto/googleapis/rpc/status"
 )
 
-type adsStream = v3adsgrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+// serverFeatureDeltaGRPC is the server feature, advertised in the bootstrap
+// file, which indicates that the incremental (Delta) xDS transport protocol
+// variant should be used to talk to this management server instead of the
+// state-of-the-world (SotW) variant.
+const serverFeatureDeltaGRPC = "delta_grpc"
 
 // Transport provides a resource-type agnostic implementation of the xDS
 // transport protocol. At this layer, resource contents are supposed to be
 // opaque blobs which should be be meaningful only to the xDS data model layer
 // which is implemented by the `xdsresource` package.
 //
-// Under the hood, it owns the gRPC connection to a single management server and
-// manages the lifecycle of ADS/LRS streams. It uses the xDS v3 transport
-// protocol version.
+// Under the hood, it owns a StreamTransport to a single management server and
+// manages the lifecycle of the ADS/LRS streams on top of it. It uses the xDS
+// v3 transport protocol version.
 type Transport struct {
 	// These fields are initialized at creation time and are read-only afterwards.
-	cc                  *grpc.ClientConn        // ClientConn to the mangement server.
-	serverURI           string                  // URI of the management server.
-	updateHandler       UpdateHandlerFunc       // Resource update handler. xDS data model layer.
-	adsStreamErrHandler func(error)             // To report underlying stream errors.
-	lrsStore            *load.Store             // Store returned to user for pushing loads.
-	backoff             func(int) time.Duration // Backoff after stream failures.
-	nodeProto           *v3corepb.Node          // Identifies the gRPC application.
-	logger              *grpclog.PrefixLogger   // Prefix logger for transport logs.
-	adsRunnerCancel     context.CancelFunc      // CancelFunc for the ADS goroutine.
-	adsRunnerDoneCh     chan struct{}           // To notify exit of ADS goroutine.
-	lrsRunnerDoneCh     chan struct{}           // To notify exit of LRS goroutine.
+	streamTransport       StreamTransport         // Creates Streams to the management server.
+	serverURI             string                  // URI of the management server.
+	updateHandler         UpdateHandlerFunc       // Resource update handler. xDS data model layer.
+	deltaUpdateHandler    DeltaUpdateHandlerFunc  // Resource update handler for the delta protocol variant.
+	useDeltaProtocol      bool                    // Use the incremental (Delta) xDS transport protocol variant.
+	adsStreamErrHandler   func(error)             // To report underlying stream errors.
+	onConnectivityFailure func(error)             // To report the A57 "management server unreachable" condition.
+	onChannelFailure      func()                  // Invoked on every transition into TRANSIENT_FAILURE.
+	onChannelReady        func()                  // Invoked on every transition out of TRANSIENT_FAILURE.
+	lrsStore              *load.Store             // Store returned to user for pushing loads.
+	backoff               func(int) time.Duration // Backoff after stream failures.
+	logger                *grpclog.PrefixLogger   // Prefix logger for transport logs.
+	adsRunnerCancel       context.CancelFunc      // CancelFunc for the ADS goroutine.
+	adsRunnerDoneCh       chan struct{}           // To notify exit of ADS goroutine.
+	lrsRunnerDoneCh       chan struct{}           // To notify exit of LRS goroutine.
 
 	// These channels enable synchronization amongst the different goroutines
 	// spawned by the transport, and between asynchorous events resulting from
 	// receipt of responses from the management server.
-	adsStreamCh  chan adsStream    // New ADS streams are pushed here.
-	adsRequestCh *buffer.Unbounded // Resource and ack requests are pushed here.
+	streamCh  chan Stream       // New ADS streams are pushed here.
+	requestCh *buffer.Unbounded // Resource and ack/nack requests are pushed here.
 
 	// mu guards the following runtime state maintained by the transport.
 	mu sync.Mutex
@@ -84,12 +85,32 @@ type Transport struct {
 	// versions is a map from resource type URL to the most recently ACKed
 	// version for that resource. Resource versions are a property of the
 	// resource type and not the stream, and will not be reset upon stream
-	// restarts.
+	// restarts. Only used by the SotW variant.
 	versions map[string]string
 	// nonces is a map from resource type URL to the most recently received
 	// nonce for that resource type. Nonces are a property of the ADS stream and
 	// will be reset upon stream restarts.
 	nonces map[string]string
+	// resourceVersions is a map from resource type URL to a map from resource
+	// name to the most recently ACKed version of that resource. Unlike
+	// versions (which is per resource type), the delta variant tracks an
+	// acceptance version per resource name, since a NACK only rejects the
+	// resources the management server considers invalid and leaves the
+	// client's view of the other resources untouched. Only used by the delta
+	// variant, and like versions, is not reset upon stream restarts.
+	resourceVersions map[string]map[string]string
+	// inTransientFailure records whether the channel to the management
+	// server is currently in TRANSIENT_FAILURE. Guarded by mu since it's
+	// read and written from both the ADS goroutine and the connectivity
+	// monitoring goroutine.
+	inTransientFailure bool
+	// broken records whether the management server is currently considered
+	// unreachable per gRFC A57: channel in TRANSIENT_FAILURE and the ADS
+	// stream failing without a single response received. Reset when the
+	// channel leaves TRANSIENT_FAILURE, and when an ADS stream on which at
+	// least one message was received ends (the same condition that resets
+	// the stream backoff).
+	broken bool
 
 	lrsMu           sync.Mutex         // Protects all LRS state.
 	lrsCancelStream context.CancelFunc // CancelFunc for the LRS stream.
@@ -105,6 +126,9 @@ type Transport struct {
 // cause the transport layer to send an ACK to the management server. A non-nil
 // error is returned from this function when the data model layer believes
 // otherwise, and this will cause the transport layer to send a NACK.
+//
+// Used for the state-of-the-world (SotW) protocol variant only; see
+// DeltaUpdateHandlerFunc for the delta variant.
 type UpdateHandlerFunc func(update ResourceUpdate) error
 
 // ResourceUpdate is a representation of the configuration update received from
@@ -120,16 +144,90 @@ type ResourceUpdate struct {
 	Version string
 }
 
+// Resource is a single named resource as received from, or accepted by, the
+// management server over the delta xDS transport protocol variant.
+type Resource struct {
+	// Name is the resource name, as it appears in the xDS response.
+	Name string
+	// Version is the resource-level version, used by the client to report
+	// which version of a given resource it has most recently accepted.
+	Version string
+	// Proto is the resource contents.
+	Proto *anypb.Any
+}
+
+// DeltaResourceUpdate is a representation of an incremental configuration
+// update received from the management server over a delta (incremental) ADS
+// stream. Unlike ResourceUpdate, which always carries the full set of
+// resources of a given type, DeltaResourceUpdate only carries what changed
+// since the last update: resources that were added or updated, and resources
+// that were removed.
+//
+// No xdsresource watcher is wired up to a DeltaUpdateHandlerFunc yet; that
+// plumbing, and the corresponding per-resource-removal notification to
+// LDS/CDS watchers, is follow-up work.
+type DeltaResourceUpdate struct {
+	// URL is the resource type URL for the below resources.
+	URL string
+	// Resources is the list of resources which were added or updated.
+	Resources []*Resource
+	// RemovedResources is the list of resource names which are no longer
+	// present on the management server.
+	RemovedResources []string
+	// Nonce is the value to be echoed back to the management server in the
+	// next request on this stream, as an acknowledgement of this update.
+	Nonce string
+	// SystemVersionInfo is the version of the entire response, as reported by
+	// the management server. This is informational only and plays no part in
+	// per-resource ACK/NACK tracking.
+	SystemVersionInfo string
+}
+
+// DeltaUpdateHandlerFunc is the implementation at the xDS data model layer,
+// which determines if the configuration received from the management server,
+// over the delta xDS transport protocol variant, can be applied locally or
+// not.
+//
+// A nil error is returned from this function when the data model layer
+// believes that the received configuration is good and can be applied
+// locally. This will cause the transport layer to ACK the resources present
+// in DeltaResourceUpdate.Resources. A non-nil error indicates otherwise, and
+// will cause the transport layer to NACK the entire update; per the xDS
+// transport protocol, client-side versions only advance for resources the
+// client actually accepted, so a NACK does not roll back previously accepted
+// resources.
+type DeltaUpdateHandlerFunc func(update DeltaResourceUpdate) error
+
 // Options specifies configuration knobs used when creating a new Transport.
 type Options struct {
 	// ServerCfg contains all the configuration required to connect to the xDS
 	// management server.
 	ServerCfg bootstrap.ServerConfig
 	// UpdateHandler is the component which makes ACK/NACK decisions based on
-	// the received resources.
+	// the received resources. Used when the transport is configured to use
+	// the state-of-the-world (SotW) protocol variant.
 	//
 	// Invoked inline and implementations must not block.
 	UpdateHandler UpdateHandlerFunc
+	// DeltaUpdateHandler is the component which makes ACK/NACK decisions based
+	// on the received resources. Used when the transport is configured to use
+	// the delta (incremental) protocol variant, i.e. when UseDeltaProtocol is
+	// set, or when ServerCfg advertises the "delta_grpc" server feature.
+	//
+	// Invoked inline and implementations must not block.
+	DeltaUpdateHandler DeltaUpdateHandlerFunc
+	// UseDeltaProtocol forces the transport to use the delta (incremental)
+	// ADS transport protocol variant (DeltaAggregatedResources) instead of
+	// the state-of-the-world variant (StreamAggregatedResources). If unset,
+	// the decision is made based on whether ServerCfg advertises the
+	// "delta_grpc" server feature.
+	UseDeltaProtocol bool
+	// TransportBuilder, if set, is used to create the StreamTransport used by
+	// this Transport, in place of the default gRPC based SotW or delta ADS
+	// implementation. This allows alternative xDS control-plane protocols
+	// (e.g. FileTransportBuilder, or an xds-relay-style aggregating proxy
+	// client) to be plugged in without forking the xdsclient.
+	TransportBuilder TransportBuilder
 	// StreamErrorHandler provides a way for the transport layer to report
 	// underlying stream errors. These can be bubbled all the way up to the user
 	// of the xdsClient.
@@ -146,38 +244,48 @@ type Options struct {
 	// NodeProto contains the Node proto to be used in xDS requests. This will be
 	// of type *v3corepb.Node.
 	NodeProto *v3corepb.Node
+	// OnConnectivityFailure, if set, is invoked once the management server is
+	// considered unreachable per gRFC A57: the underlying channel has
+	// transitioned to TRANSIENT_FAILURE and the ADS stream has failed
+	// without a single response ever being received on it. This is the
+	// signal the xdsclient uses to mark outstanding resources as
+	// does-not-exist, instead of leaving watchers hanging indefinitely.
+	//
+	// Invoked inline and implementations must not block.
+	OnConnectivityFailure func(error)
+	// OnChannelFailure, if set, is invoked every time the underlying channel
+	// transitions into TRANSIENT_FAILURE.
+	//
+	// Invoked inline and implementations must not block.
+	OnChannelFailure func()
+	// OnChannelReady, if set, is invoked every time the underlying channel
+	// transitions out of TRANSIENT_FAILURE. Together with OnChannelFailure,
+	// this allows higher layers (e.g. per-resource-type watchers) to pause
+	// and resume their own ADS response timers based on channel health.
+	//
+	// Invoked inline and implementations must not block.
+	OnChannelReady func()
 }
 
-// For overriding in unit tests.
-var grpcDial = grpc.Dial
-
 // New creates a new Transport.
 func New(opts Options) (*Transport, error) {
+	useDelta := opts.UseDeltaProtocol || serverUsesDeltaProtocol(opts.ServerCfg)
 	switch {
-	case opts.ServerCfg.ServerURI == "":
-		return nil, errors.New("missing server URI when creating a new transport")
-	case opts.ServerCfg.Creds == nil:
-		return nil, errors.New("missing credentials when creating a new transport")
-	case opts.UpdateHandler == nil:
+	case useDelta && opts.DeltaUpdateHandler == nil:
+		return nil, errors.New("missing delta update handler when creating a new transport configured to use the delta protocol")
+	case !useDelta && opts.UpdateHandler == nil:
 		return nil, errors.New("missing update handler when creating a new transport")
 	case opts.StreamErrorHandler == nil:
 		return nil, errors.New("missing stream error handler when creating a new transport")
 	}
 
-	// Dial the xDS management with the passed in credentials.
-	dopts := []grpc.DialOption{
-		opts.ServerCfg.Creds,
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			// We decided to use these sane defaults in all languages, and
-			// kicked the can down the road as far making these configurable.
-			Time:    5 * time.Minute,
-			Timeout: 20 * time.Second,
-		}),
+	builder := opts.TransportBuilder
+	if builder == nil {
+		builder = defaultTransportBuilder(useDelta)
 	}
-	cc, err := grpcDial(opts.ServerCfg.ServerURI, dopts...)
+	st, err := builder.Build(opts)
 	if err != nil {
-		// An error from a non-blocking dial indicates something serious.
-		return nil, fmt.Errorf("failed to create a transport to the management server %q: %v", opts.ServerCfg.ServerURI, err)
+		return nil, err
 	}
 
 	boff := opts.Backoff
@@ -185,21 +293,26 @@ func New(opts Options) (*Transport, error) {
 		boff = backoff.DefaultExponential.Backoff
 	}
 	ret := &Transport{
-		cc:                  cc,
-		serverURI:           opts.ServerCfg.ServerURI,
-		updateHandler:       opts.UpdateHandler,
-		adsStreamErrHandler: opts.StreamErrorHandler,
-		lrsStore:            load.NewStore(),
-		backoff:             boff,
-		nodeProto:           opts.NodeProto,
-		logger:              opts.Logger,
-
-		adsStreamCh:     make(chan adsStream, 1),
-		adsRequestCh:    buffer.NewUnbounded(),
-		resources:       make(map[string]map[string]bool),
-		versions:        make(map[string]string),
-		nonces:          make(map[string]string),
-		adsRunnerDoneCh: make(chan struct{}),
+		streamTransport:       st,
+		serverURI:             opts.ServerCfg.ServerURI,
+		updateHandler:         opts.UpdateHandler,
+		deltaUpdateHandler:    opts.DeltaUpdateHandler,
+		useDeltaProtocol:      useDelta,
+		adsStreamErrHandler:   opts.StreamErrorHandler,
+		onConnectivityFailure: opts.OnConnectivityFailure,
+		onChannelFailure:      opts.OnChannelFailure,
+		onChannelReady:        opts.OnChannelReady,
+		lrsStore:              load.NewStore(),
+		backoff:               boff,
+		logger:                opts.Logger,
+
+		streamCh:         make(chan Stream, 1),
+		requestCh:        buffer.NewUnbounded(),
+		resources:        make(map[string]map[string]bool),
+		versions:         make(map[string]string),
+		nonces:           make(map[string]string),
+		resourceVersions: make(map[string]map[string]string),
+		adsRunnerDoneCh:  make(chan struct{}),
 	}
 
 	// This context is used for sending and receiving RPC requests and
@@ -215,6 +328,18 @@ func New(opts Options) (*Transport, error) {
 	return ret, nil
 }
 
+// serverUsesDeltaProtocol reports whether the management server, as
+// configured in cfg, should be talked to using the delta (incremental) xDS
+// transport protocol variant instead of the state-of-the-world variant.
+func serverUsesDeltaProtocol(cfg bootstrap.ServerConfig) bool {
+	for _, f := range cfg.ServerFeatures {
+		if f == serverFeatureDeltaGRPC {
+			return true
+		}
+	}
+	return false
+}
+
 // resourceRequest wraps the resource type url and the resource names requested
 // by the user of this transport.
 type resourceRequest struct {
@@ -233,53 +358,12 @@ type resourceRequest struct {
 // creation time is invoked. If an error is encountered, the stream error
 // handler callback provided at creation time is invoked.
 func (t *Transport) SendRequest(url string, resources []string) {
-	t.adsRequestCh.Put(&resourceRequest{
+	t.requestCh.Put(&resourceRequest{
 		url:       url,
 		resources: resources,
 	})
 }
 
-func (t *Transport) newAggregatedDiscoveryServiceStream(ctx context.Context, cc *grpc.ClientConn) (adsStream, error) {
-	// The transport retries the stream with an exponential backoff whenever the
-	// stream breaks. But if the channel is broken, we don't want the backoff
-	// logic to continuously retry the stream. Setting WaitForReady() blocks the
-	// stream creation until the channel is READY.
-	//
-	// TODO(easwars): Make changes required to comply with A57:
-	// https://github.com/grpc/proposal/blob/master/A57-xds-client-failure-mode-behavior.md
-	return v3adsgrpc.NewAggregatedDiscoveryServiceClient(cc).StreamAggregatedResources(ctx, grpc.WaitForReady(true))
-}
-
-func (t *Transport) sendAggregatedDiscoveryServiceRequest(stream adsStream, resourceNames []string, resourceURL, version, nonce string, nackErr error) error {
-	req := &v3discoverypb.DiscoveryRequest{
-		Node:          t.nodeProto,
-		TypeUrl:       resourceURL,
-		ResourceNames: resourceNames,
-		VersionInfo:   version,
-		ResponseNonce: nonce,
-	}
-	if nackErr != nil {
-		req.ErrorDetail = &statuspb.Status{
-			Code: int32(codes.InvalidArgument), Message: nackErr.Error(),
-		}
-	}
-	if err := stream.Send(req); err != nil {
-		return fmt.Errorf("sending ADS request %s failed: %v", pretty.ToJSON(req), err)
-	}
-	t.logger.Debugf("ADS request sent: %v", pretty.ToJSON(req))
-	return nil
-}
-
-func (t *Transport) recvAggregatedDiscoveryServiceResponse(stream adsStream) (resources []*anypb.Any, resourceURL, version, nonce string, err error) {
-	resp, err := stream.Recv()
-	if err != nil {
-		return nil, "", "", "", fmt.Errorf("failed to read ADS response: %v", err)
-	}
-	t.logger.Infof("ADS response received, type: %v", resp.GetTypeUrl())
-	t.logger.Debugf("ADS response received: %v", pretty.ToJSON(resp))
-	return resp.GetResources(), resp.GetTypeUrl(), resp.GetVersionInfo(), resp.GetNonce(), nil
-}
-
 // adsRunner starts an ADS stream (and backs off exponentially, if the previous
 // stream failed without receiving a single reply) and runs the sender and
 // receiver routines to send and receive data from the stream respectively.
@@ -287,9 +371,7 @@ func (t *Transport) adsRunner(ctx context.Context) {
 	defer close(t.adsRunnerDoneCh)
 
 	go t.send(ctx)
-
-	// TODO: start a goroutine monitoring ClientConn's connectivity state, and
-	// report error (and log) when stats is transient failure.
+	go t.connectivityStateMonitor(ctx)
 
 	backoffAttempt := 0
 	backoffTimer := time.NewTimer(0)
@@ -304,7 +386,7 @@ func (t *Transport) adsRunner(ctx context.Context) {
 		// We reset backoff state when we successfully receive at least one
 		// message from the server.
 		resetBackoff := func() bool {
-			stream, err := t.newAggregatedDiscoveryServiceStream(ctx, t.cc)
+			stream, err := t.streamTransport.NewStream(ctx)
 			if err != nil {
 				t.adsStreamErrHandler(err)
 				t.logger.Warningf("ADS stream creation failed: %v", err)
@@ -313,19 +395,99 @@ func (t *Transport) adsRunner(ctx context.Context) {
 			t.logger.Infof("ADS stream created")
 
 			select {
-			case <-t.adsStreamCh:
+			case <-t.streamCh:
 			default:
 			}
-			t.adsStreamCh <- stream
+			t.streamCh <- stream
 			return t.recv(stream)
 		}()
 
 		if resetBackoff {
 			backoffTimer.Reset(0)
+			// A message was received on this attempt: the management server
+			// is reachable again, if it was ever considered broken.
+			t.mu.Lock()
+			t.broken = false
+			t.mu.Unlock()
 			backoffAttempt = 0
 		} else {
 			backoffTimer.Reset(t.backoff(backoffAttempt))
 			backoffAttempt++
+
+			// Per gRFC A57, the management server is only considered
+			// unreachable once the channel has transitioned to
+			// TRANSIENT_FAILURE *and* the ADS stream has failed without
+			// receiving a single response on it.
+			t.mu.Lock()
+			shouldReport := t.inTransientFailure && !t.broken
+			if shouldReport {
+				t.broken = true
+			}
+			t.mu.Unlock()
+			if shouldReport {
+				err := fmt.Errorf("xds: management server %q unreachable: channel is in TRANSIENT_FAILURE and no response was received on the ADS stream", t.serverURI)
+				t.adsStreamErrHandler(err)
+				if t.onConnectivityFailure != nil {
+					t.onConnectivityFailure(err)
+				}
+			}
+		}
+	}
+}
+
+// connectivityStateMonitor watches the connectivity state of the underlying
+// channel to the management server, implementing the gRFC A57 xDS client
+// failure-mode behavior. StreamTransport implementations which have no
+// notion of a gRPC channel (e.g. FileTransport) don't implement
+// connectivityStateWatcher, in which case this is a no-op.
+func (t *Transport) connectivityStateMonitor(ctx context.Context) {
+	w, ok := t.streamTransport.(connectivityStateWatcher)
+	if !ok {
+		return
+	}
+
+	current := w.GetState()
+
+	// Seed state from the initial read: the channel may already be in
+	// TRANSIENT_FAILURE by the time this goroutine gets scheduled (e.g. an
+	// unreachable ServerURI), and waiting for a subsequent transition before
+	// reacting would delay the A57 failure signal indefinitely.
+	t.mu.Lock()
+	t.inTransientFailure = current == connectivity.TransientFailure
+	alreadyInTransientFailure := t.inTransientFailure
+	t.mu.Unlock()
+	if alreadyInTransientFailure {
+		t.logger.Warningf("Channel to the management server %q is in TRANSIENT_FAILURE", t.serverURI)
+		if t.onChannelFailure != nil {
+			t.onChannelFailure()
+		}
+	}
+
+	for ctx.Err() == nil {
+		if !w.WaitForStateChange(ctx, current) {
+			// ctx was cancelled.
+			return
+		}
+		current = w.GetState()
+
+		t.mu.Lock()
+		wasInTransientFailure := t.inTransientFailure
+		t.inTransientFailure = current == connectivity.TransientFailure
+		if !t.inTransientFailure {
+			t.broken = false
+		}
+		t.mu.Unlock()
+
+		switch {
+		case t.inTransientFailure && !wasInTransientFailure:
+			t.logger.Warningf("Channel to the management server %q is in TRANSIENT_FAILURE", t.serverURI)
+			if t.onChannelFailure != nil {
+				t.onChannelFailure()
+			}
+		case !t.inTransientFailure && wasInTransientFailure:
+			if t.onChannelReady != nil {
+				t.onChannelReady()
+			}
 		}
 	}
 }
@@ -339,35 +501,31 @@ func (t *Transport) adsRunner(ctx context.Context) {
 // resources map is updated (this ensures that resend will pick them up when
 // there are new streams) and the appropriate request is sent out.
 func (t *Transport) send(ctx context.Context) {
-	var stream adsStream
+	var stream Stream
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case stream = <-t.adsStreamCh:
+		case stream = <-t.streamCh:
 			if !t.sendExisting(stream) {
 				// Send failed, clear the current stream. Attempt to resend will
 				// only be made after a new stream is created.
 				stream = nil
 			}
-		case u := <-t.adsRequestCh.Get():
-			t.adsRequestCh.Load()
-
-			var (
-				resources           []string
-				url, version, nonce string
-				send                bool
-				nackErr             error
-			)
+		case u := <-t.requestCh.Get():
+			t.requestCh.Load()
+
+			var req *DiscoveryRequest
 			switch update := u.(type) {
 			case *resourceRequest:
-				resources, url, version, nonce = t.processResourceRequest(update)
+				req = t.processResourceRequest(update)
 			case *ackRequest:
-				resources, url, version, nonce, send = t.processAckRequest(update, stream)
+				var send bool
+				req, send = t.processAckRequest(update, stream)
 				if !send {
 					continue
 				}
-				nackErr = update.nackErr
+				req.ErrorDetail = update.nackErr
 			}
 			if stream == nil {
 				// There's no stream yet. Skip the request. This request
@@ -376,8 +534,8 @@ func (t *Transport) send(ctx context.Context) {
 				// sending response back).
 				continue
 			}
-			if err := t.sendAggregatedDiscoveryServiceRequest(stream, resources, url, version, nonce, nackErr); err != nil {
-				t.logger.Warningf("ADS request for {resources: %q, url: %v, version: %q, nonce: %q} failed: %v", resources, url, version, nonce, err)
+			if err := stream.Send(req); err != nil {
+				t.logger.Warningf("ADS request for {url: %v, resources: %q} failed: %v", req.TypeURL, req.ResourceNames, err)
 				// Send failed, clear the current stream.
 				stream = nil
 			}
@@ -392,7 +550,7 @@ func (t *Transport) send(ctx context.Context) {
 // that here because the stream has just started and Send() usually returns
 // quickly (once it pushes the message onto the transport layer) and is only
 // ever blocked if we don't have enough flow control quota.
-func (t *Transport) sendExisting(stream adsStream) bool {
+func (t *Transport) sendExisting(stream Stream) bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -409,7 +567,27 @@ func (t *Transport) sendExisting(stream adsStream) bool {
 	t.nonces = make(map[string]string)
 
 	for url, resources := range t.resources {
-		if err := t.sendAggregatedDiscoveryServiceRequest(stream, mapToSlice(resources), url, t.versions[url], "", nil); err != nil {
+		req := &DiscoveryRequest{
+			TypeURL:       url,
+			ResourceNames: mapToSlice(resources),
+			VersionInfo:   t.versions[url],
+		}
+		if t.useDeltaProtocol {
+			// Only report versions for resources we're still subscribed to.
+			// t.resourceVersions[url] is pruned as subscriptions change (see
+			// processResourceRequest), but be defensive here too: reporting
+			// a version for a resource we no longer want would incorrectly
+			// tell the management server we already have it.
+			versions := t.resourceVersions[url]
+			initial := make(map[string]string, len(resources))
+			for name := range resources {
+				if v, ok := versions[name]; ok {
+					initial[name] = v
+				}
+			}
+			req.InitialResourceVersions = initial
+		}
+		if err := stream.Send(req); err != nil {
 			t.logger.Warningf("ADS request failed: %v", err)
 			return false
 		}
@@ -421,10 +599,10 @@ func (t *Transport) sendExisting(stream adsStream) bool {
 // recv receives xDS responses on the provided ADS stream and branches out to
 // message specific handlers. Returns true if at least one message was
 // successfully received.
-func (t *Transport) recv(stream adsStream) bool {
+func (t *Transport) recv(stream Stream) bool {
 	msgReceived := false
 	for {
-		resources, url, rVersion, nonce, err := t.recvAggregatedDiscoveryServiceResponse(stream)
+		resp, err := stream.Recv()
 		if err != nil {
 			t.adsStreamErrHandler(err)
 			t.logger.Warningf("ADS stream is closed with error: %v", err)
@@ -432,11 +610,21 @@ func (t *Transport) recv(stream adsStream) bool {
 		}
 		msgReceived = true
 
-		err = t.updateHandler(ResourceUpdate{
-			Resources: resources,
-			URL:       url,
-			Version:   rVersion,
-		})
+		if t.useDeltaProtocol {
+			err = t.deltaUpdateHandler(DeltaResourceUpdate{
+				URL:               resp.TypeURL,
+				Resources:         resp.Added,
+				RemovedResources:  resp.RemovedResources,
+				Nonce:             resp.Nonce,
+				SystemVersionInfo: resp.SystemVersionInfo,
+			})
+		} else {
+			err = t.updateHandler(ResourceUpdate{
+				Resources: resp.Resources,
+				URL:       resp.TypeURL,
+				Version:   resp.VersionInfo,
+			})
+		}
 		if xdsresource.ErrType(err) == xdsresource.ErrorTypeResourceTypeUnsupported {
 			t.logger.Warningf("%v", err)
 			continue
@@ -446,24 +634,41 @@ func (t *Transport) recv(stream adsStream) bool {
 		// recently accepted version of this resource type.
 		if err != nil {
 			t.mu.Lock()
-			t.adsRequestCh.Put(&ackRequest{
-				url:     url,
-				nonce:   nonce,
+			t.requestCh.Put(&ackRequest{
+				url:     resp.TypeURL,
+				nonce:   resp.Nonce,
 				stream:  stream,
-				version: t.versions[url],
+				version: t.versions[resp.TypeURL],
 				nackErr: err,
 			})
 			t.mu.Unlock()
-			t.logger.Warningf("Sending NACK for resource type: %v, version: %v, nonce: %v, reason: %v", url, rVersion, nonce, err)
+			t.logger.Warningf("Sending NACK for resource type: %v, nonce: %v, reason: %v", resp.TypeURL, resp.Nonce, err)
 			continue
 		}
-		t.adsRequestCh.Put(&ackRequest{
-			url:     url,
-			nonce:   nonce,
+
+		if t.useDeltaProtocol {
+			t.mu.Lock()
+			m := t.resourceVersions[resp.TypeURL]
+			if m == nil {
+				m = make(map[string]string)
+				t.resourceVersions[resp.TypeURL] = m
+			}
+			for _, r := range resp.Added {
+				m[r.Name] = r.Version
+			}
+			for _, name := range resp.RemovedResources {
+				delete(m, name)
+			}
+			t.mu.Unlock()
+		}
+
+		t.requestCh.Put(&ackRequest{
+			url:     resp.TypeURL,
+			nonce:   resp.Nonce,
 			stream:  stream,
-			version: rVersion,
+			version: resp.VersionInfo,
 		})
-		t.logger.Infof("Sending ACK for resource type: %v, version: %v, nonce: %v", url, rVersion, nonce)
+		t.logger.Infof("Sending ACK for resource type: %v, version: %v, nonce: %v", resp.TypeURL, resp.VersionInfo, resp.Nonce)
 	}
 }
 
@@ -489,41 +694,70 @@ func sliceToMap(ss []string) map[string]bool {
 //
 // The resources map, which keeps track of the resources being requested, is
 // updated here. Any subsequent stream failure will re-request resources stored
-// in this map.
-//
-// Returns the list of resources, resource type url, version and nonce.
-func (t *Transport) processResourceRequest(req *resourceRequest) ([]string, string, string, string) {
+// in this map. For the delta protocol variant, only the newly
+// subscribed/unsubscribed resource names are returned, since the full set was
+// already communicated to the management server.
+func (t *Transport) processResourceRequest(req *resourceRequest) *DiscoveryRequest {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	resources := sliceToMap(req.resources)
-	t.resources[req.url] = resources
-	return req.resources, req.url, t.versions[req.url], t.nonces[req.url]
+	newSet := sliceToMap(req.resources)
+	out := &DiscoveryRequest{
+		TypeURL:       req.url,
+		VersionInfo:   t.versions[req.url],
+		ResponseNonce: t.nonces[req.url],
+	}
+	if t.useDeltaProtocol {
+		oldSet := t.resources[req.url]
+		for name := range newSet {
+			if !oldSet[name] {
+				out.ResourceNames = append(out.ResourceNames, name)
+			}
+		}
+		versions := t.resourceVersions[req.url]
+		for name := range oldSet {
+			if !newSet[name] {
+				out.ResourceNamesUnsubscribe = append(out.ResourceNamesUnsubscribe, name)
+				// The client no longer cares about this resource's version;
+				// keeping it around would leak memory across repeated
+				// subscribe/unsubscribe cycles and would cause the next
+				// stream restart to report InitialResourceVersions for
+				// resources we're no longer subscribed to.
+				delete(versions, name)
+			}
+		}
+	} else {
+		out.ResourceNames = req.resources
+	}
+
+	t.resources[req.url] = newSet
+	return out
 }
 
+// ackRequest wraps the fields needed to send out an ADS ACK/NACK.
 type ackRequest struct {
 	url     string // Resource type URL.
-	version string // NACK if version is an empty string.
+	version string // NACK if version is an empty string. Unused by the delta variant.
 	nonce   string
 	nackErr error // nil for ACK, non-nil for NACK.
 	// ACK/NACK are tagged with the stream it's for. When the stream is down,
 	// all the ACK/NACK for this stream will be dropped, and the version/nonce
 	// won't be updated.
-	stream grpc.ClientStream
+	stream Stream
 }
 
 // processAckRequest pulls the fields needed to send out an ADS ACK. The nonces
 // and versions map is updated.
 //
-// Returns the list of resources, resource type url, version, nonce, and an
-// indication of whether an ACK should be sent on the wire or not.
-func (t *Transport) processAckRequest(ack *ackRequest, stream grpc.ClientStream) ([]string, string, string, string, bool) {
+// Returns the request to send on the wire, and an indication of whether an
+// ACK/NACK should actually be sent or not.
+func (t *Transport) processAckRequest(ack *ackRequest, stream Stream) (*DiscoveryRequest, bool) {
 	if ack.stream != stream {
 		// If ACK's stream isn't the current sending stream, this means the ACK
 		// was pushed to queue before the old stream broke, and a new stream has
 		// been started since. Return immediately here so we don't update the
 		// nonce for the new stream.
-		return nil, "", "", "", false
+		return nil, false
 	}
 
 	t.mu.Lock()
@@ -531,8 +765,13 @@ func (t *Transport) processAckRequest(ack *ackRequest, stream grpc.ClientStream)
 
 	// Update the nonce irrespective of whether we send the ACK request on wire.
 	// An up-to-date nonce is required for the next request.
-	nonce := ack.nonce
-	t.nonces[ack.url] = nonce
+	t.nonces[ack.url] = ack.nonce
+
+	if t.useDeltaProtocol {
+		// Delta ACK/NACKs don't resend the subscription list; per-resource
+		// acceptance versions are tracked separately, in recv.
+		return &DiscoveryRequest{TypeURL: ack.url, ResponseNonce: ack.nonce}, true
+	}
 
 	s, ok := t.resources[ack.url]
 	if !ok || len(s) == 0 {
@@ -542,29 +781,39 @@ func (t *Transport) processAckRequest(ack *ackRequest, stream grpc.ClientStream)
 		// ackRequest was in queue). If we send a request with an empty
 		// resource name list, the server may treat it as a wild card and send
 		// us everything.
-		return nil, "", "", "", false
+		return nil, false
 	}
-	resources := mapToSlice(s)
 
 	// Update the versions map only when we plan to send an ACK.
 	if ack.nackErr == nil {
 		t.versions[ack.url] = ack.version
 	}
 
-	return resources, ack.url, ack.version, nonce, true
+	return &DiscoveryRequest{
+		TypeURL:       ack.url,
+		ResourceNames: mapToSlice(s),
+		VersionInfo:   ack.version,
+		ResponseNonce: ack.nonce,
+	}, true
 }
 
 // Close closes the Transport and frees any associated resources.
 func (t *Transport) Close() {
 	t.adsRunnerCancel()
 	<-t.adsRunnerDoneCh
-	t.cc.Close()
+	t.streamTransport.Close()
 }
 
-// ChannelConnectivityStateForTesting returns the connectivity state of the gRPC
-// channel to the management server.
+// ChannelConnectivityStateForTesting returns the connectivity state of the
+// underlying channel to the management server, or connectivity.Idle if the
+// configured StreamTransport has no notion of connectivity state (e.g.
+// FileTransport).
 //
 // Only for testing purposes.
 func (t *Transport) ChannelConnectivityStateForTesting() connectivity.State {
-	return t.cc.GetState()
+	w, ok := t.streamTransport.(connectivityStateWatcher)
+	if !ok {
+		return connectivity.Idle
+	}
+	return w.GetState()
 }
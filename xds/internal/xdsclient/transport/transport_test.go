@@ -0,0 +1,107 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeStream is a Stream implementation which records every request it is
+// asked to send. Recv is never exercised by the tests in this file.
+type fakeStream struct {
+	mu   sync.Mutex
+	sent []*DiscoveryRequest
+}
+
+func (f *fakeStream) Send(req *DiscoveryRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, req)
+	return nil
+}
+
+func (f *fakeStream) Recv() (*DiscoveryResponse, error) {
+	panic("Recv unexpectedly called on fakeStream")
+}
+
+// TestSendExistingFiltersInitialResourceVersions verifies that, when
+// resending existing subscriptions on a restarted delta stream,
+// InitialResourceVersions only reports versions for resources the client is
+// still subscribed to, and not for resources it has since unsubscribed from.
+func TestSendExistingFiltersInitialResourceVersions(t *testing.T) {
+	tr := &Transport{
+		useDeltaProtocol: true,
+		resources: map[string]map[string]bool{
+			"type.A": {"res1": true},
+		},
+		versions: map[string]string{},
+		nonces:   map[string]string{"type.A": "stale-nonce"},
+		resourceVersions: map[string]map[string]string{
+			"type.A": {"res1": "v1", "res2": "v2"},
+		},
+	}
+
+	stream := &fakeStream{}
+	if !tr.sendExisting(stream) {
+		t.Fatalf("sendExisting() = false, want true")
+	}
+	if len(stream.sent) != 1 {
+		t.Fatalf("got %d requests sent, want 1", len(stream.sent))
+	}
+
+	got := stream.sent[0].InitialResourceVersions
+	want := map[string]string{"res1": "v1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InitialResourceVersions = %v, want %v (res2 is no longer subscribed to)", got, want)
+	}
+	if len(tr.nonces) != 0 {
+		t.Errorf("nonces = %v, want empty map after stream restart", tr.nonces)
+	}
+}
+
+// TestProcessResourceRequestPrunesResourceVersionsOnUnsubscribe verifies that
+// unsubscribing from a resource removes its entry from resourceVersions, so
+// that unsubscribed resources neither leak memory nor reappear in a later
+// InitialResourceVersions.
+func TestProcessResourceRequestPrunesResourceVersionsOnUnsubscribe(t *testing.T) {
+	tr := &Transport{
+		useDeltaProtocol: true,
+		resources: map[string]map[string]bool{
+			"type.A": {"res1": true, "res2": true},
+		},
+		versions: map[string]string{},
+		nonces:   map[string]string{},
+		resourceVersions: map[string]map[string]string{
+			"type.A": {"res1": "v1", "res2": "v2"},
+		},
+	}
+
+	req := tr.processResourceRequest(&resourceRequest{url: "type.A", resources: []string{"res1"}})
+
+	if got, want := sliceToMap(req.ResourceNamesUnsubscribe), sliceToMap([]string{"res2"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResourceNamesUnsubscribe = %v, want [res2]", req.ResourceNamesUnsubscribe)
+	}
+	if _, ok := tr.resourceVersions["type.A"]["res2"]; ok {
+		t.Errorf("resourceVersions[%q] still contains unsubscribed resource %q, want pruned", "type.A", "res2")
+	}
+	if _, ok := tr.resourceVersions["type.A"]["res1"]; !ok {
+		t.Errorf("resourceVersions[%q] no longer contains still-subscribed resource %q", "type.A", "res1")
+	}
+}